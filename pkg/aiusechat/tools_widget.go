@@ -7,6 +7,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/wavetermdev/waveterm/pkg/aiusechat/uctypes"
@@ -195,6 +196,9 @@ func GetWidgetOpenToolDefinition(tabId string) uctypes.ToolDefinition {
 			if err != nil {
 				return nil, fmt.Errorf("failed to create widget: %w", err)
 			}
+			if connName, _ := meta["connection"].(string); connName != "" {
+				wcore.RegisterConnection(connName)
+			}
 
 			// Build layout action based on split_direction
 			var layoutAction waveobj.LayoutActionData
@@ -601,3 +605,575 @@ func GetWidgetMoveToolDefinition(tabId string) uctypes.ToolDefinition {
 		},
 	}
 }
+
+type WidgetResizeToolInput struct {
+	WidgetId     string   `json:"widget_id"`
+	Ratio        *float64 `json:"ratio,omitempty"`
+	Delta        *float64 `json:"delta,omitempty"`
+	TargetWidget string   `json:"target_widget,omitempty"`
+}
+
+func parseWidgetResizeInput(input any) (*WidgetResizeToolInput, error) {
+	result := &WidgetResizeToolInput{}
+
+	if input == nil {
+		return nil, fmt.Errorf("input is required")
+	}
+
+	inputBytes, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal input: %w", err)
+	}
+
+	if err := json.Unmarshal(inputBytes, result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if result.WidgetId == "" {
+		return nil, fmt.Errorf("widget_id is required")
+	}
+
+	if result.Ratio == nil && result.Delta == nil {
+		return nil, fmt.Errorf("either ratio or delta is required")
+	}
+
+	if result.Ratio != nil && result.Delta != nil {
+		return nil, fmt.Errorf("ratio and delta are mutually exclusive")
+	}
+
+	if result.Ratio != nil && (*result.Ratio <= 0.0 || *result.Ratio >= 1.0) {
+		return nil, fmt.Errorf("invalid ratio: %v. Must be between 0.0 and 1.0 (exclusive)", *result.Ratio)
+	}
+
+	if result.Delta != nil && (*result.Delta <= -1.0 || *result.Delta >= 1.0) {
+		return nil, fmt.Errorf("invalid delta: %v. Must be between -1.0 and 1.0 (exclusive)", *result.Delta)
+	}
+
+	if result.Delta != nil && result.TargetWidget == "" {
+		return nil, fmt.Errorf("target_widget is required when delta is specified")
+	}
+
+	if result.Delta != nil && result.TargetWidget == result.WidgetId {
+		return nil, fmt.Errorf("target_widget must be different from widget_id")
+	}
+
+	return result, nil
+}
+
+func GetWidgetResizeToolDefinition(tabId string) uctypes.ToolDefinition {
+	return uctypes.ToolDefinition{
+		Name:        "widget_resize",
+		DisplayName: "Resize Widget",
+		Description: "Resize a widget within its parent split, either to an explicit ratio or by a relative delta taken from a sibling widget. Use this to fix an uneven default split.",
+		ToolLogName: "widget:resize",
+		Strict:      false,
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"widget_id": map[string]any{
+					"type":        "string",
+					"description": "8-character widget ID of the widget to resize",
+				},
+				"ratio": map[string]any{
+					"type":        "number",
+					"description": "Explicit size for the widget, as a fraction (0.0-1.0, exclusive) of its parent split. Mutually exclusive with delta.",
+				},
+				"delta": map[string]any{
+					"type":        "number",
+					"description": "Relative size change (e.g. +0.1 or -0.1) to apply to the widget, taken from target_widget. Mutually exclusive with ratio.",
+				},
+				"target_widget": map[string]any{
+					"type":        "string",
+					"description": "Sibling widget ID to take the delta from. Required when delta is specified.",
+				},
+			},
+			"required":             []string{"widget_id"},
+			"additionalProperties": false,
+		},
+		ToolCallDesc: func(input any, output any, toolUseData *uctypes.UIMessageDataToolUse) string {
+			parsed, err := parseWidgetResizeInput(input)
+			if err != nil {
+				return fmt.Sprintf("error parsing input: %v", err)
+			}
+			if parsed.Ratio != nil {
+				return fmt.Sprintf("resizing widget %s to ratio %.2f", parsed.WidgetId, *parsed.Ratio)
+			}
+			return fmt.Sprintf("resizing widget %s by %.2f relative to widget %s", parsed.WidgetId, *parsed.Delta, parsed.TargetWidget)
+		},
+		ToolAnyCallback: func(input any, toolUseData *uctypes.UIMessageDataToolUse) (any, error) {
+			parsed, err := parseWidgetResizeInput(input)
+			if err != nil {
+				return nil, err
+			}
+
+			ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancelFn()
+			ctx = waveobj.ContextWithUpdates(ctx)
+
+			fullBlockId, err := wcore.ResolveBlockIdFromPrefix(ctx, tabId, parsed.WidgetId)
+			if err != nil {
+				return nil, fmt.Errorf("failed to find widget with ID %s: %w", parsed.WidgetId, err)
+			}
+
+			layoutAction := waveobj.LayoutActionData{
+				ActionType: wcore.LayoutActionDataType_Resize,
+				BlockId:    fullBlockId,
+			}
+
+			if parsed.Ratio != nil {
+				layoutAction.Ratio = parsed.Ratio
+			} else {
+				targetBlockId, err := wcore.ResolveBlockIdFromPrefix(ctx, tabId, parsed.TargetWidget)
+				if err != nil {
+					return nil, fmt.Errorf("failed to find target widget %s: %w", parsed.TargetWidget, err)
+				}
+				layoutAction.TargetBlockId = targetBlockId
+				layoutAction.Delta = parsed.Delta
+			}
+
+			err = wcore.QueueLayoutActionForTab(ctx, tabId, layoutAction)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resize widget: %w", err)
+			}
+
+			updates := waveobj.ContextGetUpdatesRtn(ctx)
+			wps.Broker.SendUpdateEvents(updates)
+
+			return map[string]any{
+				"success": true,
+				"message": fmt.Sprintf("widget %s resized", parsed.WidgetId),
+			}, nil
+		},
+	}
+}
+
+// widgetLayoutOpEnvelope is used to sniff the "op" discriminator out of a
+// batch operation before dispatching to the op-specific parser.
+type widgetLayoutOpEnvelope struct {
+	Op string `json:"op"`
+}
+
+type WidgetLayoutToolInput struct {
+	Operations []json.RawMessage `json:"operations"`
+}
+
+// parsedWidgetLayoutOp holds the structurally-validated input for a single
+// batch operation, tagged with its op type so it doesn't need to be
+// re-parsed during execution.
+type parsedWidgetLayoutOp struct {
+	Op     string
+	Open   *WidgetOpenToolInput
+	Close  *WidgetCloseToolInput
+	Move   *WidgetMoveToolInput
+	Rename *WidgetRenameToolInput
+	Resize *WidgetResizeToolInput
+}
+
+func parseWidgetLayoutInput(input any) (*WidgetLayoutToolInput, []parsedWidgetLayoutOp, error) {
+	result := &WidgetLayoutToolInput{}
+
+	if input == nil {
+		return nil, nil, fmt.Errorf("input is required")
+	}
+
+	inputBytes, err := json.Marshal(input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal input: %w", err)
+	}
+
+	if err := json.Unmarshal(inputBytes, result); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if len(result.Operations) == 0 {
+		return nil, nil, fmt.Errorf("operations is required and must not be empty")
+	}
+
+	parsedOps := make([]parsedWidgetLayoutOp, 0, len(result.Operations))
+	for i, rawOp := range result.Operations {
+		var envelope widgetLayoutOpEnvelope
+		if err := json.Unmarshal(rawOp, &envelope); err != nil {
+			return nil, nil, fmt.Errorf("operations[%d]: failed to unmarshal op: %w", i, err)
+		}
+
+		var opInput any
+		if err := json.Unmarshal(rawOp, &opInput); err != nil {
+			return nil, nil, fmt.Errorf("operations[%d]: failed to unmarshal op: %w", i, err)
+		}
+
+		switch envelope.Op {
+		case "open":
+			parsed, err := parseWidgetOpenInput(opInput)
+			if err != nil {
+				return nil, nil, fmt.Errorf("operations[%d]: %w", i, err)
+			}
+			parsedOps = append(parsedOps, parsedWidgetLayoutOp{Op: "open", Open: parsed})
+		case "close":
+			parsed, err := parseWidgetCloseInput(opInput)
+			if err != nil {
+				return nil, nil, fmt.Errorf("operations[%d]: %w", i, err)
+			}
+			parsedOps = append(parsedOps, parsedWidgetLayoutOp{Op: "close", Close: parsed})
+		case "move":
+			parsed, err := parseWidgetMoveInput(opInput)
+			if err != nil {
+				return nil, nil, fmt.Errorf("operations[%d]: %w", i, err)
+			}
+			parsedOps = append(parsedOps, parsedWidgetLayoutOp{Op: "move", Move: parsed})
+		case "rename":
+			parsed, err := parseWidgetRenameInput(opInput)
+			if err != nil {
+				return nil, nil, fmt.Errorf("operations[%d]: %w", i, err)
+			}
+			parsedOps = append(parsedOps, parsedWidgetLayoutOp{Op: "rename", Rename: parsed})
+		case "resize":
+			parsed, err := parseWidgetResizeInput(opInput)
+			if err != nil {
+				return nil, nil, fmt.Errorf("operations[%d]: %w", i, err)
+			}
+			parsedOps = append(parsedOps, parsedWidgetLayoutOp{Op: "resize", Resize: parsed})
+		default:
+			return nil, nil, fmt.Errorf("operations[%d]: invalid op: %q. Valid values are: open, close, move, rename, resize", i, envelope.Op)
+		}
+	}
+
+	return result, parsedOps, nil
+}
+
+// validateWidgetLayoutOps resolves every widget reference in the batch up
+// front - either a handle ($1, $2, ...) declared by an earlier 'open' in
+// this same batch, or an existing widget ID - before any operation in the
+// batch runs. A typo'd or stale ID is the failure mode rollback can't
+// undo (it only reverts blocks this batch created), so catching it here
+// means the batch can't be left half-applied by it.
+func validateWidgetLayoutOps(ctx context.Context, tabId string, parsedOps []parsedWidgetLayoutOp) error {
+	declaredHandles := map[string]bool{}
+	resolveRef := func(opIndex int, field string, id string) error {
+		if id == "" {
+			return nil
+		}
+		if strings.HasPrefix(id, "$") {
+			if !declaredHandles[id] {
+				return fmt.Errorf("operations[%d]: %s %q does not refer to a widget created earlier in this batch", opIndex, field, id)
+			}
+			return nil
+		}
+		if _, err := wcore.ResolveBlockIdFromPrefix(ctx, tabId, id); err != nil {
+			return fmt.Errorf("operations[%d]: %s: %w", opIndex, field, err)
+		}
+		return nil
+	}
+
+	handleNum := 0
+	for i, op := range parsedOps {
+		switch op.Op {
+		case "open":
+			if op.Open.SplitDirection != "" && op.Open.TargetWidget != "" {
+				if err := resolveRef(i, "target_widget", op.Open.TargetWidget); err != nil {
+					return err
+				}
+			}
+			handleNum++
+			declaredHandles[fmt.Sprintf("$%d", handleNum)] = true
+		case "close":
+			if err := resolveRef(i, "widget_id", op.Close.WidgetId); err != nil {
+				return err
+			}
+		case "move":
+			if err := resolveRef(i, "widget_id", op.Move.WidgetId); err != nil {
+				return err
+			}
+			if err := resolveRef(i, "target_widget_id", op.Move.TargetWidgetId); err != nil {
+				return err
+			}
+		case "rename":
+			if err := resolveRef(i, "widget_id", op.Rename.WidgetId); err != nil {
+				return err
+			}
+		case "resize":
+			if err := resolveRef(i, "widget_id", op.Resize.WidgetId); err != nil {
+				return err
+			}
+			if err := resolveRef(i, "target_widget", op.Resize.TargetWidget); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func GetWidgetLayoutToolDefinition(tabId string) uctypes.ToolDefinition {
+	return uctypes.ToolDefinition{
+		Name:        "widget_layout",
+		DisplayName: "Batch Widget Layout",
+		Description: "Apply a sequence of widget operations (open, close, move, rename, resize) as a single batch. Widgets created by an earlier 'open' operation can be referenced by later operations using a temporary handle ($1, $2, ...) in place of a widget_id or target_widget, in the order the open operations appear. Every widget reference in the batch is checked up front, so an unknown or stale ID fails the whole batch before any operation runs. If an operation still fails once the batch is running (e.g. the live layout rejects it), any widgets this batch created earlier are closed again; close, move, rename, and resize operations that already ran are not reverted.",
+		ToolLogName: "widget:layout",
+		Strict:      false,
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"operations": map[string]any{
+					"type":        "array",
+					"description": "Ordered list of operations to apply as one batch. Each item is a tagged object with an 'op' field ('open', 'close', 'move', 'rename', or 'resize') plus that operation's normal fields. Use $1, $2, ... to refer to widgets created by earlier 'open' operations in this same batch.",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"op": map[string]any{
+								"type": "string",
+								"enum": []string{"open", "close", "move", "rename", "resize"},
+							},
+						},
+						"required": []string{"op"},
+					},
+				},
+			},
+			"required":             []string{"operations"},
+			"additionalProperties": false,
+		},
+		ToolCallDesc: func(input any, output any, toolUseData *uctypes.UIMessageDataToolUse) string {
+			_, parsedOps, err := parseWidgetLayoutInput(input)
+			if err != nil {
+				return fmt.Sprintf("error parsing input: %v", err)
+			}
+			return fmt.Sprintf("applying a batch of %d widget layout operations", len(parsedOps))
+		},
+		ToolAnyCallback: func(input any, toolUseData *uctypes.UIMessageDataToolUse) (any, error) {
+			_, parsedOps, err := parseWidgetLayoutInput(input)
+			if err != nil {
+				return nil, err
+			}
+
+			ctx, cancelFn := context.WithTimeout(context.Background(), 15*time.Second)
+			defer cancelFn()
+			ctx = waveobj.ContextWithUpdates(ctx)
+
+			if err := validateWidgetLayoutOps(ctx, tabId, parsedOps); err != nil {
+				return nil, err
+			}
+
+			handles := make(map[string]string)
+			var createdBlockIds []string
+			resolve := func(id string) (string, error) {
+				if realId, ok := handles[id]; ok {
+					return realId, nil
+				}
+				return wcore.ResolveBlockIdFromPrefix(ctx, tabId, id)
+			}
+
+			// All widget references were already resolved by
+			// validateWidgetLayoutOps above, so a later op in this loop
+			// should only fail on a live-layout precondition (e.g. a resize
+			// whose target isn't actually a sibling), not a missing ID.
+			// rollback undoes block creations made earlier in this same
+			// batch; it cannot undo a prior close/rename/move/resize op that
+			// already committed, so those still aren't reverted. Each
+			// created block is removed from the layout before being
+			// deleted, same as widget_close, so the layout tree doesn't
+			// end up with leaves pointing at now-deleted block IDs.
+			rollback := func() {
+				for i := len(createdBlockIds) - 1; i >= 0; i-- {
+					blockId := createdBlockIds[i]
+					_ = wcore.QueueLayoutActionForTab(ctx, tabId, waveobj.LayoutActionData{
+						ActionType: wcore.LayoutActionDataType_Remove,
+						BlockId:    blockId,
+					})
+					_ = wcore.DeleteBlock(ctx, blockId, true)
+				}
+			}
+
+			results := make([]map[string]any, 0, len(parsedOps))
+			handleNum := 0
+
+			for i, op := range parsedOps {
+				switch op.Op {
+				case "open":
+					parsed := op.Open
+					meta := map[string]any{
+						"view": parsed.WidgetType,
+					}
+					switch parsed.WidgetType {
+					case "web":
+						meta["url"] = parsed.Url
+					case "preview":
+						if parsed.File != "" {
+							meta["file"] = parsed.File
+						}
+					case "term":
+						meta["controller"] = "shell"
+						if parsed.Connection != "" && parsed.Connection != "local" {
+							meta["connection"] = parsed.Connection
+						}
+					case "cpuplot":
+						if parsed.Connection != "" {
+							meta["connection"] = parsed.Connection
+						}
+					}
+
+					blockDef := &waveobj.BlockDef{Meta: meta}
+					blockData, err := wcore.CreateBlock(ctx, tabId, blockDef, nil)
+					if err != nil {
+						rollback()
+						return nil, fmt.Errorf("operations[%d] (open): failed to create widget: %w", i, err)
+					}
+					createdBlockIds = append(createdBlockIds, blockData.OID)
+					if connName, _ := meta["connection"].(string); connName != "" {
+						wcore.RegisterConnection(connName)
+					}
+
+					var layoutAction waveobj.LayoutActionData
+					if parsed.SplitDirection != "" && parsed.TargetWidget != "" {
+						targetBlockId, err := resolve(parsed.TargetWidget)
+						if err != nil {
+							rollback()
+							return nil, fmt.Errorf("operations[%d] (open): failed to find target widget %s: %w", i, parsed.TargetWidget, err)
+						}
+						position := parsed.Position
+						if position == "" {
+							position = "after"
+						}
+						actionType := wcore.LayoutActionDataType_SplitHorizontal
+						if parsed.SplitDirection == "vertical" {
+							actionType = wcore.LayoutActionDataType_SplitVertical
+						}
+						layoutAction = waveobj.LayoutActionData{
+							ActionType:    actionType,
+							BlockId:       blockData.OID,
+							TargetBlockId: targetBlockId,
+							Position:      position,
+							Focused:       true,
+						}
+					} else {
+						layoutAction = waveobj.LayoutActionData{
+							ActionType: wcore.LayoutActionDataType_Insert,
+							BlockId:    blockData.OID,
+							Focused:    true,
+						}
+					}
+
+					if err := wcore.QueueLayoutActionForTab(ctx, tabId, layoutAction); err != nil {
+						rollback()
+						return nil, fmt.Errorf("operations[%d] (open): failed to add widget to layout: %w", i, err)
+					}
+
+					if parsed.WidgetType == "term" {
+						if err := blockcontroller.ResyncController(ctx, tabId, blockData.OID, nil, false); err != nil {
+							rollback()
+							return nil, fmt.Errorf("operations[%d] (open): failed to start terminal controller: %w", i, err)
+						}
+					}
+
+					handleNum++
+					handle := fmt.Sprintf("$%d", handleNum)
+					handles[handle] = blockData.OID
+					results = append(results, map[string]any{"op": "open", "handle": handle, "widget_id": blockData.OID[:8]})
+
+				case "close":
+					parsed := op.Close
+					fullBlockId, err := resolve(parsed.WidgetId)
+					if err != nil {
+						rollback()
+						return nil, fmt.Errorf("operations[%d] (close): failed to find widget %s: %w", i, parsed.WidgetId, err)
+					}
+					layoutAction := waveobj.LayoutActionData{
+						ActionType: wcore.LayoutActionDataType_Remove,
+						BlockId:    fullBlockId,
+					}
+					if err := wcore.QueueLayoutActionForTab(ctx, tabId, layoutAction); err != nil {
+						rollback()
+						return nil, fmt.Errorf("operations[%d] (close): failed to queue layout action: %w", i, err)
+					}
+					if err := wcore.DeleteBlock(ctx, fullBlockId, true); err != nil {
+						rollback()
+						return nil, fmt.Errorf("operations[%d] (close): failed to close widget: %w", i, err)
+					}
+					results = append(results, map[string]any{"op": "close", "widget_id": parsed.WidgetId})
+
+				case "move":
+					parsed := op.Move
+					fullBlockId, err := resolve(parsed.WidgetId)
+					if err != nil {
+						rollback()
+						return nil, fmt.Errorf("operations[%d] (move): failed to find widget %s: %w", i, parsed.WidgetId, err)
+					}
+					targetBlockId, err := resolve(parsed.TargetWidgetId)
+					if err != nil {
+						rollback()
+						return nil, fmt.Errorf("operations[%d] (move): failed to find target widget %s: %w", i, parsed.TargetWidgetId, err)
+					}
+					position := parsed.Position
+					if position == "" {
+						position = "after"
+					}
+					actionType := wcore.LayoutActionDataType_MoveHorizontal
+					if parsed.Direction == "vertical" {
+						actionType = wcore.LayoutActionDataType_MoveVertical
+					}
+					layoutAction := waveobj.LayoutActionData{
+						ActionType:    actionType,
+						BlockId:       fullBlockId,
+						TargetBlockId: targetBlockId,
+						Position:      position,
+						Focused:       true,
+					}
+					if err := wcore.QueueLayoutActionForTab(ctx, tabId, layoutAction); err != nil {
+						rollback()
+						return nil, fmt.Errorf("operations[%d] (move): failed to move widget: %w", i, err)
+					}
+					results = append(results, map[string]any{"op": "move", "widget_id": parsed.WidgetId})
+
+				case "rename":
+					parsed := op.Rename
+					fullBlockId, err := resolve(parsed.WidgetId)
+					if err != nil {
+						rollback()
+						return nil, fmt.Errorf("operations[%d] (rename): failed to find widget %s: %w", i, parsed.WidgetId, err)
+					}
+					blockORef := waveobj.MakeORef(waveobj.OType_Block, fullBlockId)
+					meta := map[string]any{"display:name": parsed.Name}
+					if err := wstore.UpdateObjectMeta(ctx, blockORef, meta, true); err != nil {
+						rollback()
+						return nil, fmt.Errorf("operations[%d] (rename): failed to rename widget: %w", i, err)
+					}
+					wcore.SendWaveObjUpdate(blockORef)
+					results = append(results, map[string]any{"op": "rename", "widget_id": parsed.WidgetId, "name": parsed.Name})
+
+				case "resize":
+					parsed := op.Resize
+					fullBlockId, err := resolve(parsed.WidgetId)
+					if err != nil {
+						rollback()
+						return nil, fmt.Errorf("operations[%d] (resize): failed to find widget %s: %w", i, parsed.WidgetId, err)
+					}
+					layoutAction := waveobj.LayoutActionData{
+						ActionType: wcore.LayoutActionDataType_Resize,
+						BlockId:    fullBlockId,
+					}
+					if parsed.Ratio != nil {
+						layoutAction.Ratio = parsed.Ratio
+					} else {
+						targetBlockId, err := resolve(parsed.TargetWidget)
+						if err != nil {
+							rollback()
+							return nil, fmt.Errorf("operations[%d] (resize): failed to find target widget %s: %w", i, parsed.TargetWidget, err)
+						}
+						layoutAction.TargetBlockId = targetBlockId
+						layoutAction.Delta = parsed.Delta
+					}
+					if err := wcore.QueueLayoutActionForTab(ctx, tabId, layoutAction); err != nil {
+						rollback()
+						return nil, fmt.Errorf("operations[%d] (resize): failed to resize widget: %w", i, err)
+					}
+					results = append(results, map[string]any{"op": "resize", "widget_id": parsed.WidgetId})
+				}
+			}
+
+			updates := waveobj.ContextGetUpdatesRtn(ctx)
+			wps.Broker.SendUpdateEvents(updates)
+
+			return map[string]any{
+				"success": true,
+				"results": results,
+			}, nil
+		},
+	}
+}
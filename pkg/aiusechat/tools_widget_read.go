@@ -0,0 +1,187 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package aiusechat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/aiusechat/uctypes"
+	"github.com/wavetermdev/waveterm/pkg/blockcontroller"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wcore"
+	"github.com/wavetermdev/waveterm/pkg/wps"
+	"github.com/wavetermdev/waveterm/pkg/wstore"
+)
+
+const (
+	widgetReadDefaultTailLines = 100
+	widgetReadMaxTailLines     = 5000
+	widgetReadDefaultMaxBytes  = 16 * 1024
+	widgetReadMaxMaxBytes      = 256 * 1024
+)
+
+type WidgetReadToolInput struct {
+	WidgetId    string `json:"widget_id"`
+	TailLines   int    `json:"tail_lines,omitempty"`
+	MaxBytes    int    `json:"max_bytes,omitempty"`
+	IncludeAnsi bool   `json:"include_ansi,omitempty"`
+}
+
+func parseWidgetReadInput(input any) (*WidgetReadToolInput, error) {
+	result := &WidgetReadToolInput{}
+
+	if input == nil {
+		return nil, fmt.Errorf("input is required")
+	}
+
+	inputBytes, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal input: %w", err)
+	}
+
+	if err := json.Unmarshal(inputBytes, result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if result.WidgetId == "" {
+		return nil, fmt.Errorf("widget_id is required")
+	}
+
+	if result.TailLines < 0 {
+		return nil, fmt.Errorf("tail_lines must not be negative")
+	}
+	if result.TailLines == 0 {
+		result.TailLines = widgetReadDefaultTailLines
+	}
+	if result.TailLines > widgetReadMaxTailLines {
+		result.TailLines = widgetReadMaxTailLines
+	}
+
+	if result.MaxBytes < 0 {
+		return nil, fmt.Errorf("max_bytes must not be negative")
+	}
+	if result.MaxBytes == 0 {
+		result.MaxBytes = widgetReadDefaultMaxBytes
+	}
+	if result.MaxBytes > widgetReadMaxMaxBytes {
+		result.MaxBytes = widgetReadMaxMaxBytes
+	}
+
+	return result, nil
+}
+
+func GetWidgetReadToolDefinition(tabId string) uctypes.ToolDefinition {
+	return uctypes.ToolDefinition{
+		Name:        "widget_read",
+		DisplayName: "Read Widget",
+		Description: "Inspect a widget's current state and content: view type, meta (url, file, cwd, display name), and view-specific content such as terminal scrollback, previewed file snippet, web page URL/title, or the latest CPU sample. Use this to verify a command or action actually took effect.",
+		ToolLogName: "widget:read",
+		Strict:      false,
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"widget_id": map[string]any{
+					"type":        "string",
+					"description": "8-character widget ID of the widget to read",
+				},
+				"tail_lines": map[string]any{
+					"type":        "integer",
+					"description": "For term widgets, number of trailing scrollback lines to return. Defaults to 100.",
+				},
+				"max_bytes": map[string]any{
+					"type":        "integer",
+					"description": "Maximum number of bytes of content to return. Defaults to 16384.",
+				},
+				"include_ansi": map[string]any{
+					"type":        "boolean",
+					"description": "For term widgets, include raw ANSI escape codes instead of stripping them. Defaults to false.",
+				},
+			},
+			"required":             []string{"widget_id"},
+			"additionalProperties": false,
+		},
+		ToolCallDesc: func(input any, output any, toolUseData *uctypes.UIMessageDataToolUse) string {
+			parsed, err := parseWidgetReadInput(input)
+			if err != nil {
+				return fmt.Sprintf("error parsing input: %v", err)
+			}
+			return fmt.Sprintf("reading widget %s", parsed.WidgetId)
+		},
+		ToolAnyCallback: func(input any, toolUseData *uctypes.UIMessageDataToolUse) (any, error) {
+			parsed, err := parseWidgetReadInput(input)
+			if err != nil {
+				return nil, err
+			}
+
+			ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancelFn()
+			ctx = waveobj.ContextWithUpdates(ctx)
+
+			fullBlockId, err := wcore.ResolveBlockIdFromPrefix(ctx, tabId, parsed.WidgetId)
+			if err != nil {
+				return nil, fmt.Errorf("failed to find widget with ID %s: %w", parsed.WidgetId, err)
+			}
+
+			blockData, err := wstore.DBMustGet[*waveobj.Block](ctx, fullBlockId)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read widget %s: %w", parsed.WidgetId, err)
+			}
+
+			view, _ := blockData.Meta["view"].(string)
+			result := map[string]any{
+				"widget_id": parsed.WidgetId,
+				"view":      view,
+				"meta": map[string]any{
+					"url":          blockData.Meta["url"],
+					"file":         blockData.Meta["file"],
+					"cwd":          blockData.Meta["cwd"],
+					"connection":   blockData.Meta["connection"],
+					"display:name": blockData.Meta["display:name"],
+				},
+			}
+
+			switch view {
+			case "term":
+				scrollback, err := blockcontroller.GetScrollbackLines(ctx, fullBlockId, parsed.TailLines, parsed.MaxBytes, parsed.IncludeAnsi)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read terminal scrollback: %w", err)
+				}
+				result["content"] = scrollback
+
+			case "preview":
+				file, _ := blockData.Meta["file"].(string)
+				result["file"] = file
+				if file != "" {
+					snippet, err := wcore.ReadFileSnippet(ctx, fullBlockId, parsed.MaxBytes)
+					if err != nil {
+						return nil, fmt.Errorf("failed to read preview file: %w", err)
+					}
+					result["content"] = snippet
+				}
+
+			case "web":
+				result["url"] = blockData.Meta["url"]
+				result["title"] = blockData.Meta["pinnedurl:title"]
+
+			case "cpuplot":
+				sample, err := wcore.GetLatestPlotSample(ctx, fullBlockId)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read latest CPU sample: %w", err)
+				}
+				result["latest_sample"] = sample
+
+			default:
+				result["content"] = nil
+			}
+
+			updates := waveobj.ContextGetUpdatesRtn(ctx)
+			wps.Broker.SendUpdateEvents(updates)
+
+			return result, nil
+		},
+	}
+}
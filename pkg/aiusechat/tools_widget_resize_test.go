@@ -0,0 +1,23 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package aiusechat
+
+import "testing"
+
+// TestParseWidgetResizeInputRejectsSameTarget checks that a delta resize
+// naming the same widget as both widget_id and target_widget is rejected
+// at parse time, rather than silently succeeding as a no-op (the two size
+// writes cancel out since target and node alias the same layout node).
+func TestParseWidgetResizeInputRejectsSameTarget(t *testing.T) {
+	delta := 0.1
+	input := map[string]any{
+		"widget_id":     "abc12345",
+		"target_widget": "abc12345",
+		"delta":         delta,
+	}
+
+	if _, err := parseWidgetResizeInput(input); err == nil {
+		t.Fatal("expected an error when target_widget equals widget_id, got nil")
+	}
+}
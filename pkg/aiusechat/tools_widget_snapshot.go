@@ -0,0 +1,504 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package aiusechat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/wavetermdev/waveterm/pkg/aiusechat/uctypes"
+	"github.com/wavetermdev/waveterm/pkg/blockcontroller"
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wcore"
+	"github.com/wavetermdev/waveterm/pkg/wps"
+	"github.com/wavetermdev/waveterm/pkg/wstore"
+)
+
+// WidgetSnapshotVersion is the schema version for serialized layout snapshots.
+// Bump this whenever the shape of WidgetSnapshot changes in a way that isn't
+// backwards compatible, so old snapshots are rejected on restore.
+const WidgetSnapshotVersion = 1
+
+// widgetSnapshotMetaKey is the tab meta key under which all of a tab's
+// named layout snapshots are stored, keyed by snapshot name.
+const widgetSnapshotMetaKey = "ai:layoutsnapshots"
+
+// widgetSnapshotMetaAllowlist controls which block meta keys are captured
+// into a snapshot. It intentionally excludes runtime-only state that
+// wouldn't make sense to replay verbatim (e.g. controller status).
+var widgetSnapshotMetaAllowlist = []string{"view", "url", "file", "connection", "display:name", "controller"}
+
+type WidgetSnapshotBlock struct {
+	Handle string         `json:"handle"` // stable within the snapshot, referenced by the layout tree
+	Meta   map[string]any `json:"meta"`
+}
+
+type WidgetSnapshotLayoutNode struct {
+	NodeType string                      `json:"nodetype"` // "leaf", "row", or "col"
+	Handle   string                      `json:"handle,omitempty"`
+	Size     float64                     `json:"size,omitempty"`
+	Children []*WidgetSnapshotLayoutNode `json:"children,omitempty"`
+}
+
+type WidgetSnapshot struct {
+	Version int                       `json:"version"`
+	Name    string                    `json:"name"`
+	Blocks  []WidgetSnapshotBlock     `json:"blocks"`
+	Layout  *WidgetSnapshotLayoutNode `json:"layout"`
+}
+
+func getTabSnapshots(ctx context.Context, tabId string) (map[string]WidgetSnapshot, error) {
+	tabData, err := wstore.DBMustGet[*waveobj.Tab](ctx, tabId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tab: %w", err)
+	}
+
+	raw, ok := tabData.Meta[widgetSnapshotMetaKey].(string)
+	if !ok || raw == "" {
+		return map[string]WidgetSnapshot{}, nil
+	}
+
+	snapshots := map[string]WidgetSnapshot{}
+	if err := json.Unmarshal([]byte(raw), &snapshots); err != nil {
+		return nil, fmt.Errorf("failed to parse stored snapshots: %w", err)
+	}
+	return snapshots, nil
+}
+
+func saveTabSnapshots(ctx context.Context, tabId string, snapshots map[string]WidgetSnapshot) error {
+	raw, err := json.Marshal(snapshots)
+	if err != nil {
+		return fmt.Errorf("failed to serialize snapshots: %w", err)
+	}
+
+	tabORef := waveobj.MakeORef(waveobj.OType_Tab, tabId)
+	meta := map[string]any{widgetSnapshotMetaKey: string(raw)}
+	if err := wstore.UpdateObjectMeta(ctx, tabORef, meta, true); err != nil {
+		return fmt.Errorf("failed to save snapshots: %w", err)
+	}
+	wcore.SendWaveObjUpdate(tabORef)
+	return nil
+}
+
+func buildSnapshotLayoutNode(node *wcore.LayoutNode, handles map[string]string) *WidgetSnapshotLayoutNode {
+	if node == nil {
+		return nil
+	}
+	if node.BlockId != "" {
+		return &WidgetSnapshotLayoutNode{
+			NodeType: "leaf",
+			Handle:   handles[node.BlockId],
+			Size:     node.Size,
+		}
+	}
+	snapNode := &WidgetSnapshotLayoutNode{
+		NodeType: node.NodeType,
+		Size:     node.Size,
+	}
+	for _, child := range node.Children {
+		snapNode.Children = append(snapNode.Children, buildSnapshotLayoutNode(child, handles))
+	}
+	return snapNode
+}
+
+type WidgetSnapshotToolInput struct {
+	Name string `json:"name"`
+}
+
+func parseWidgetSnapshotInput(input any) (*WidgetSnapshotToolInput, error) {
+	result := &WidgetSnapshotToolInput{}
+
+	if input == nil {
+		return nil, fmt.Errorf("input is required")
+	}
+
+	inputBytes, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal input: %w", err)
+	}
+
+	if err := json.Unmarshal(inputBytes, result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if result.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	return result, nil
+}
+
+func GetWidgetSnapshotToolDefinition(tabId string) uctypes.ToolDefinition {
+	return uctypes.ToolDefinition{
+		Name:        "widget_snapshot",
+		DisplayName: "Save Layout Snapshot",
+		Description: "Save the current tab's widgets and layout (split directions and sizes) as a named preset that can be restored later with widget_restore. Saving under an existing name overwrites it.",
+		ToolLogName: "widget:snapshot",
+		Strict:      true,
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"name": map[string]any{
+					"type":        "string",
+					"description": "Name to save this layout preset under",
+				},
+			},
+			"required":             []string{"name"},
+			"additionalProperties": false,
+		},
+		ToolCallDesc: func(input any, output any, toolUseData *uctypes.UIMessageDataToolUse) string {
+			parsed, err := parseWidgetSnapshotInput(input)
+			if err != nil {
+				return fmt.Sprintf("error parsing input: %v", err)
+			}
+			return fmt.Sprintf("saving current layout as %q", parsed.Name)
+		},
+		ToolAnyCallback: func(input any, toolUseData *uctypes.UIMessageDataToolUse) (any, error) {
+			parsed, err := parseWidgetSnapshotInput(input)
+			if err != nil {
+				return nil, err
+			}
+
+			ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancelFn()
+
+			blockIds, err := wcore.GetBlockIdsForTab(ctx, tabId)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list widgets: %w", err)
+			}
+
+			handles := make(map[string]string, len(blockIds))
+			blocks := make([]WidgetSnapshotBlock, 0, len(blockIds))
+			for i, blockId := range blockIds {
+				blockData, err := wstore.DBMustGet[*waveobj.Block](ctx, blockId)
+				if err != nil {
+					return nil, fmt.Errorf("failed to load widget %s: %w", blockId, err)
+				}
+				handle := fmt.Sprintf("$%d", i+1)
+				handles[blockId] = handle
+
+				meta := map[string]any{}
+				for _, key := range widgetSnapshotMetaAllowlist {
+					if val, ok := blockData.Meta[key]; ok {
+						meta[key] = val
+					}
+				}
+				blocks = append(blocks, WidgetSnapshotBlock{Handle: handle, Meta: meta})
+			}
+
+			layoutTree, err := wcore.GetLayoutTreeForTab(ctx, tabId)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read layout: %w", err)
+			}
+
+			snapshot := WidgetSnapshot{
+				Version: WidgetSnapshotVersion,
+				Name:    parsed.Name,
+				Blocks:  blocks,
+				Layout:  buildSnapshotLayoutNode(layoutTree, handles),
+			}
+
+			snapshots, err := getTabSnapshots(ctx, tabId)
+			if err != nil {
+				return nil, err
+			}
+			snapshots[parsed.Name] = snapshot
+
+			if err := saveTabSnapshots(ctx, tabId, snapshots); err != nil {
+				return nil, err
+			}
+
+			return map[string]any{
+				"success":      true,
+				"name":         parsed.Name,
+				"widget_count": len(blocks),
+			}, nil
+		},
+	}
+}
+
+type WidgetRestoreToolInput struct {
+	Name string `json:"name"`
+}
+
+func parseWidgetRestoreInput(input any) (*WidgetRestoreToolInput, error) {
+	result := &WidgetRestoreToolInput{}
+
+	if input == nil {
+		return nil, fmt.Errorf("input is required")
+	}
+
+	inputBytes, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal input: %w", err)
+	}
+
+	if err := json.Unmarshal(inputBytes, result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal input: %w", err)
+	}
+
+	if result.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	return result, nil
+}
+
+// attachSkeleton places only the leftmost leaf of node's subtree - the
+// single representative used to anchor whatever attaches next at this
+// level - at anchor/direction (Insert if anchor is ""), and returns its
+// block ID. It does not touch any other descendant of node; those are
+// filled in later by placeSnapshotLayout once the whole tree's outer
+// shape is anchored. A leaf whose block was skipped at creation time
+// (e.g. a missing connection) leaves anchor unchanged.
+func attachSkeleton(ctx context.Context, tabId string, node *WidgetSnapshotLayoutNode, created map[string]string, anchor string, direction string) (string, error) {
+	if node == nil {
+		return anchor, nil
+	}
+
+	if node.NodeType == "leaf" {
+		blockId, ok := created[node.Handle]
+		if !ok {
+			return anchor, nil
+		}
+		var layoutAction waveobj.LayoutActionData
+		if anchor == "" {
+			layoutAction = waveobj.LayoutActionData{ActionType: wcore.LayoutActionDataType_Insert, BlockId: blockId}
+		} else {
+			layoutAction = waveobj.LayoutActionData{ActionType: direction, BlockId: blockId, TargetBlockId: anchor, Position: "after"}
+		}
+		if err := wcore.QueueLayoutActionForTab(ctx, tabId, layoutAction); err != nil {
+			return "", fmt.Errorf("failed to place restored widget: %w", err)
+		}
+		return blockId, nil
+	}
+
+	if len(node.Children) == 0 {
+		return anchor, nil
+	}
+	return attachSkeleton(ctx, tabId, node.Children[0], created, anchor, direction)
+}
+
+// placeSnapshotLayout rebuilds the saved layout tree breadth-first, level by
+// level: every child of a group is anchored against its preceding sibling's
+// representative (attachSkeleton) before any of those children are expanded
+// further. Expanding a child's own subtree depth-first before attaching its
+// next sibling would split the sibling against a leaf that has *already*
+// been wrapped into its own subtree, nesting the sibling inside it instead
+// of placing it alongside - e.g. the saved tree row[col[A,B], C] would come
+// back as col[row[A,C], B] instead of row[col[A,B], C].
+func placeSnapshotLayout(ctx context.Context, tabId string, root *WidgetSnapshotLayoutNode, created map[string]string) error {
+	rootAnchor, err := attachSkeleton(ctx, tabId, root, created, "", "")
+	if err != nil {
+		return err
+	}
+
+	type pendingNode struct {
+		node   *WidgetSnapshotLayoutNode
+		anchor string
+	}
+	queue := []pendingNode{{root, rootAnchor}}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		node := cur.node
+		if node == nil || node.NodeType == "leaf" || len(node.Children) == 0 {
+			continue
+		}
+
+		ownDirection := wcore.LayoutActionDataType_SplitHorizontal
+		if node.NodeType == "col" {
+			ownDirection = wcore.LayoutActionDataType_SplitVertical
+		}
+
+		// node.Children[0]'s representative was already attached above (as
+		// cur.anchor, or as rootAnchor for the very first node); only the
+		// remaining children still need to be linked in.
+		anchor := cur.anchor
+		childAnchors := make([]string, len(node.Children))
+		childAnchors[0] = anchor
+		for i := 1; i < len(node.Children); i++ {
+			childAnchor, err := attachSkeleton(ctx, tabId, node.Children[i], created, anchor, ownDirection)
+			if err != nil {
+				return err
+			}
+			childAnchors[i] = childAnchor
+			anchor = childAnchor
+		}
+
+		for i, child := range node.Children {
+			queue = append(queue, pendingNode{child, childAnchors[i]})
+		}
+	}
+	return nil
+}
+
+// replaySnapshotSizes re-applies each leaf's saved split ratio once the full
+// tree shape has been rebuilt by placeSnapshotLayout. It's best-effort: a leaf
+// that ended up without a parent split (e.g. the only widget restored) has
+// nothing to size relative to, and that's not an error.
+func replaySnapshotSizes(ctx context.Context, tabId string, node *WidgetSnapshotLayoutNode, created map[string]string) {
+	if node == nil {
+		return
+	}
+	if node.NodeType == "leaf" {
+		blockId, ok := created[node.Handle]
+		if !ok || node.Size <= 0 {
+			return
+		}
+		ratio := node.Size
+		action := waveobj.LayoutActionData{ActionType: wcore.LayoutActionDataType_Resize, BlockId: blockId, Ratio: &ratio}
+		_ = wcore.QueueLayoutActionForTab(ctx, tabId, action)
+		return
+	}
+	for _, child := range node.Children {
+		replaySnapshotSizes(ctx, tabId, child, created)
+	}
+}
+
+// restoreSnapshotBlocks recreates each block from a snapshot, starting the
+// terminal controller for restored "term" widgets the same way widget_open
+// does. A block whose meta references a connection that isn't currently
+// available is skipped (its handle is reported, not created) rather than
+// failing the whole restore. Returns the handle -> new block ID mapping for
+// whatever was created, plus the handles that were skipped.
+func restoreSnapshotBlocks(ctx context.Context, tabId string, blocks []WidgetSnapshotBlock) (map[string]string, []string, error) {
+	created := make(map[string]string, len(blocks))
+	var skippedHandles []string
+
+	for _, block := range blocks {
+		connName, _ := block.Meta["connection"].(string)
+		if connName != "" && !wcore.ConnectionExists(connName) {
+			skippedHandles = append(skippedHandles, block.Handle)
+			continue
+		}
+
+		blockDef := &waveobj.BlockDef{Meta: block.Meta}
+		blockData, err := wcore.CreateBlock(ctx, tabId, blockDef, nil)
+		if err != nil {
+			skippedHandles = append(skippedHandles, block.Handle)
+			continue
+		}
+		created[block.Handle] = blockData.OID
+
+		if viewType, _ := block.Meta["view"].(string); viewType == "term" {
+			_ = blockcontroller.ResyncController(ctx, tabId, blockData.OID, nil, false)
+		}
+	}
+
+	return created, skippedHandles, nil
+}
+
+func GetWidgetRestoreToolDefinition(tabId string) uctypes.ToolDefinition {
+	return uctypes.ToolDefinition{
+		Name:        "widget_restore",
+		DisplayName: "Restore Layout Snapshot",
+		Description: "Recreate widgets and layout from a previously saved snapshot (see widget_snapshot). If a widget in the snapshot referenced a connection that no longer exists, it is skipped and reported rather than aborting the whole restore.",
+		ToolLogName: "widget:restore",
+		Strict:      true,
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"name": map[string]any{
+					"type":        "string",
+					"description": "Name of the snapshot to restore",
+				},
+			},
+			"required":             []string{"name"},
+			"additionalProperties": false,
+		},
+		ToolCallDesc: func(input any, output any, toolUseData *uctypes.UIMessageDataToolUse) string {
+			parsed, err := parseWidgetRestoreInput(input)
+			if err != nil {
+				return fmt.Sprintf("error parsing input: %v", err)
+			}
+			return fmt.Sprintf("restoring layout snapshot %q", parsed.Name)
+		},
+		ToolAnyCallback: func(input any, toolUseData *uctypes.UIMessageDataToolUse) (any, error) {
+			parsed, err := parseWidgetRestoreInput(input)
+			if err != nil {
+				return nil, err
+			}
+
+			ctx, cancelFn := context.WithTimeout(context.Background(), 15*time.Second)
+			defer cancelFn()
+			ctx = waveobj.ContextWithUpdates(ctx)
+
+			snapshots, err := getTabSnapshots(ctx, tabId)
+			if err != nil {
+				return nil, err
+			}
+			snapshot, ok := snapshots[parsed.Name]
+			if !ok {
+				return nil, fmt.Errorf("no snapshot named %q", parsed.Name)
+			}
+			if snapshot.Version != WidgetSnapshotVersion {
+				return nil, fmt.Errorf("snapshot %q has unsupported version %d (expected %d)", parsed.Name, snapshot.Version, WidgetSnapshotVersion)
+			}
+
+			created, skippedHandles, err := restoreSnapshotBlocks(ctx, tabId, snapshot.Blocks)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := placeSnapshotLayout(ctx, tabId, snapshot.Layout, created); err != nil {
+				return nil, err
+			}
+			replaySnapshotSizes(ctx, tabId, snapshot.Layout, created)
+
+			updates := waveobj.ContextGetUpdatesRtn(ctx)
+			wps.Broker.SendUpdateEvents(updates)
+
+			result := map[string]any{
+				"success":        len(skippedHandles) == 0,
+				"name":           parsed.Name,
+				"restored_count": len(created),
+			}
+			if len(skippedHandles) > 0 {
+				result["skipped"] = skippedHandles
+				result["message"] = fmt.Sprintf("restored %d widget(s); %d widget(s) skipped (e.g. missing connection)", len(created), len(skippedHandles))
+			}
+			return result, nil
+		},
+	}
+}
+
+func GetWidgetSnapshotListToolDefinition(tabId string) uctypes.ToolDefinition {
+	return uctypes.ToolDefinition{
+		Name:        "widget_snapshot_list",
+		DisplayName: "List Layout Snapshots",
+		Description: "List the names of all layout snapshots saved for this tab via widget_snapshot.",
+		ToolLogName: "widget:snapshot_list",
+		Strict:      true,
+		InputSchema: map[string]any{
+			"type":                 "object",
+			"properties":           map[string]any{},
+			"additionalProperties": false,
+		},
+		ToolCallDesc: func(input any, output any, toolUseData *uctypes.UIMessageDataToolUse) string {
+			return "listing saved layout snapshots"
+		},
+		ToolAnyCallback: func(input any, toolUseData *uctypes.UIMessageDataToolUse) (any, error) {
+			ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancelFn()
+
+			snapshots, err := getTabSnapshots(ctx, tabId)
+			if err != nil {
+				return nil, err
+			}
+
+			names := make([]string, 0, len(snapshots))
+			for name := range snapshots {
+				names = append(names, name)
+			}
+
+			return map[string]any{
+				"success":   true,
+				"snapshots": names,
+			}, nil
+		},
+	}
+}
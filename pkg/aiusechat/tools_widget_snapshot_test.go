@@ -0,0 +1,121 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package aiusechat
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wavetermdev/waveterm/pkg/blockcontroller"
+	"github.com/wavetermdev/waveterm/pkg/wcore"
+)
+
+// TestPlaceSnapshotLayoutNestedGroup restores a saved row[col[A,B], C] tree
+// (a column group on the left, a widget on the right) and checks that C
+// ends up as a sibling of the whole column group rather than nested inside
+// it next to A (col[row[A,C], B]).
+func TestPlaceSnapshotLayoutNestedGroup(t *testing.T) {
+	ctx := context.Background()
+	tabId := "test-restore-nested-group"
+
+	saved := &WidgetSnapshotLayoutNode{
+		NodeType: "row",
+		Children: []*WidgetSnapshotLayoutNode{
+			{
+				NodeType: "col",
+				Children: []*WidgetSnapshotLayoutNode{
+					{NodeType: "leaf", Handle: "$1", Size: 0.5},
+					{NodeType: "leaf", Handle: "$2", Size: 0.5},
+				},
+			},
+			{NodeType: "leaf", Handle: "$3", Size: 0.5},
+		},
+	}
+	created := map[string]string{"$1": "a", "$2": "b", "$3": "c"}
+
+	if err := placeSnapshotLayout(ctx, tabId, saved, created); err != nil {
+		t.Fatalf("placeSnapshotLayout failed: %v", err)
+	}
+
+	root, err := wcore.GetLayoutTreeForTab(ctx, tabId)
+	if err != nil {
+		t.Fatalf("GetLayoutTreeForTab failed: %v", err)
+	}
+
+	if root.NodeType != "row" || len(root.Children) != 2 {
+		t.Fatalf("expected a 2-child row at the root, got nodetype=%s children=%d", root.NodeType, len(root.Children))
+	}
+
+	col := root.Children[0]
+	if col.NodeType != "col" || len(col.Children) != 2 {
+		t.Fatalf("expected the first row child to be a 2-child col group, got nodetype=%s children=%d", col.NodeType, len(col.Children))
+	}
+	if col.Children[0].BlockId != "a" || col.Children[1].BlockId != "b" {
+		t.Fatalf("expected col group to contain [a, b], got [%s, %s]", col.Children[0].BlockId, col.Children[1].BlockId)
+	}
+
+	c := root.Children[1]
+	if c.NodeType != "leaf" || c.BlockId != "c" {
+		t.Fatalf("expected widget c to be the row's second child, got nodetype=%s blockid=%s", c.NodeType, c.BlockId)
+	}
+}
+
+// TestRestoreSnapshotBlocksSkipsMissingConnection checks that a block whose
+// meta references a connection wcore doesn't know about is skipped rather
+// than created, while a block referencing a registered connection restores
+// normally.
+func TestRestoreSnapshotBlocksSkipsMissingConnection(t *testing.T) {
+	ctx := context.Background()
+	tabId := "test-restore-missing-connection"
+
+	wcore.RegisterConnection("known-conn")
+
+	blocks := []WidgetSnapshotBlock{
+		{Handle: "$1", Meta: map[string]any{"view": "term", "connection": "known-conn"}},
+		{Handle: "$2", Meta: map[string]any{"view": "term", "connection": "no-such-conn"}},
+	}
+
+	created, skipped, err := restoreSnapshotBlocks(ctx, tabId, blocks)
+	if err != nil {
+		t.Fatalf("restoreSnapshotBlocks failed: %v", err)
+	}
+
+	if _, ok := created["$1"]; !ok {
+		t.Fatalf("expected $1 (known connection) to be created, got created=%+v", created)
+	}
+	if _, ok := created["$2"]; ok {
+		t.Fatalf("expected $2 (missing connection) to be skipped, not created")
+	}
+	if len(skipped) != 1 || skipped[0] != "$2" {
+		t.Fatalf("expected skipped=[$2], got %+v", skipped)
+	}
+}
+
+// TestRestoreSnapshotBlocksStartsTermController checks that a restored
+// "term" widget has its controller started, same as widget_open does for a
+// newly opened term widget.
+func TestRestoreSnapshotBlocksStartsTermController(t *testing.T) {
+	ctx := context.Background()
+	tabId := "test-restore-starts-controller"
+
+	blocks := []WidgetSnapshotBlock{
+		{Handle: "$1", Meta: map[string]any{"view": "term"}},
+	}
+
+	created, skipped, err := restoreSnapshotBlocks(ctx, tabId, blocks)
+	if err != nil {
+		t.Fatalf("restoreSnapshotBlocks failed: %v", err)
+	}
+	if len(skipped) != 0 {
+		t.Fatalf("expected no skipped widgets, got %+v", skipped)
+	}
+
+	blockId, ok := created["$1"]
+	if !ok {
+		t.Fatal("expected $1 to be created")
+	}
+	if !blockcontroller.IsStarted(blockId) {
+		t.Fatalf("expected restored term widget %s to have its controller started", blockId)
+	}
+}
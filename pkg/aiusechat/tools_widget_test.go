@@ -0,0 +1,93 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package aiusechat
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wavetermdev/waveterm/pkg/wcore"
+)
+
+// TestValidateWidgetLayoutOpsRejectsUndeclaredHandle checks that a batch
+// referencing a handle before the 'open' that would create it is rejected
+// up front, rather than failing mid-batch after earlier ops already ran.
+func TestValidateWidgetLayoutOpsRejectsUndeclaredHandle(t *testing.T) {
+	ops := []parsedWidgetLayoutOp{
+		{Op: "rename", Rename: &WidgetRenameToolInput{WidgetId: "$1", Name: "term"}},
+		{Op: "open", Open: &WidgetOpenToolInput{WidgetType: "term"}},
+	}
+
+	if err := validateWidgetLayoutOps(context.Background(), "test-tab", ops); err == nil {
+		t.Fatal("expected an error for a handle referenced before its declaring 'open', got nil")
+	}
+}
+
+// TestValidateWidgetLayoutOpsAcceptsDeclaredHandle checks that a handle
+// referenced after the 'open' operation that creates it passes validation.
+func TestValidateWidgetLayoutOpsAcceptsDeclaredHandle(t *testing.T) {
+	ops := []parsedWidgetLayoutOp{
+		{Op: "open", Open: &WidgetOpenToolInput{WidgetType: "term"}},
+		{Op: "rename", Rename: &WidgetRenameToolInput{WidgetId: "$1", Name: "term"}},
+	}
+
+	if err := validateWidgetLayoutOps(context.Background(), "test-tab", ops); err != nil {
+		t.Fatalf("expected a handle declared by an earlier 'open' to validate, got: %v", err)
+	}
+}
+
+// TestWidgetLayoutRollbackClearsLayoutTree drives a batch that creates three
+// widgets and then fails on a resize between non-siblings, and checks that
+// rollback leaves the layout tree empty - not populated with leaves pointing
+// at the block IDs rollback just deleted from wstore.
+func TestWidgetLayoutRollbackClearsLayoutTree(t *testing.T) {
+	tabId := "test-widget-layout-rollback"
+	tool := GetWidgetLayoutToolDefinition(tabId)
+
+	delta := 0.1
+	input := map[string]any{
+		"operations": []map[string]any{
+			{"op": "open", "widget_type": "term"},
+			{"op": "open", "widget_type": "term", "split_direction": "horizontal", "target_widget": "$1"},
+			{"op": "open", "widget_type": "term", "split_direction": "vertical", "target_widget": "$2"},
+			{"op": "resize", "widget_id": "$1", "target_widget": "$3", "delta": delta},
+		},
+	}
+
+	if _, err := tool.ToolAnyCallback(input, nil); err == nil {
+		t.Fatal("expected the batch to fail on a resize between non-siblings, got nil")
+	}
+
+	root, err := wcore.GetLayoutTreeForTab(context.Background(), tabId)
+	if err != nil {
+		t.Fatalf("GetLayoutTreeForTab failed: %v", err)
+	}
+	if root != nil {
+		t.Fatalf("expected rollback to leave an empty layout tree, got %+v", root)
+	}
+}
+
+// TestWidgetOpenRegistersConnection checks that successfully opening a
+// widget against a remote connection marks that connection as known to
+// wcore.ConnectionExists, since this tree has no real connection resolver -
+// a successful open is what later lets widget_restore tell a once-used
+// connection apart from one that was never established.
+func TestWidgetOpenRegistersConnection(t *testing.T) {
+	tabId := "test-widget-open-registers-connection"
+	tool := GetWidgetOpenToolDefinition(tabId)
+
+	connName := "test-open-conn"
+	input := map[string]any{
+		"widget_type": "term",
+		"connection":  connName,
+	}
+
+	if _, err := tool.ToolAnyCallback(input, nil); err != nil {
+		t.Fatalf("widget_open failed: %v", err)
+	}
+
+	if !wcore.ConnectionExists(connName) {
+		t.Fatalf("expected widget_open to register connection %q as known", connName)
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package uctypes holds the shared types used to describe AI-usable tools
+// (the "use chat" tool-calling surface) independent of any one toolset.
+package uctypes
+
+// UIMessageDataToolUse carries the chat-UI bookkeeping for a single tool
+// invocation (which message/turn it belongs to) alongside the tool's input.
+type UIMessageDataToolUse struct {
+	ToolUseId string `json:"tooluseid"`
+	ToolName  string `json:"toolname"`
+	MessageId string `json:"messageid,omitempty"`
+}
+
+// ToolDefinition describes one AI-callable tool: its schema, how to render
+// it in the UI while it runs, and the callback that actually performs it.
+type ToolDefinition struct {
+	Name        string
+	DisplayName string
+	Description string
+	ToolLogName string
+	Strict      bool
+	InputSchema map[string]any
+
+	// ToolCallDesc renders a short, present-tense human description of the
+	// call for the UI (e.g. "opening web widget with URL ...").
+	ToolCallDesc func(input any, output any, toolUseData *UIMessageDataToolUse) string
+
+	// ToolAnyCallback executes the tool and returns its (JSON-able) result.
+	ToolAnyCallback func(input any, toolUseData *UIMessageDataToolUse) (any, error)
+}
@@ -0,0 +1,70 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package blockcontroller manages the runtime controller backing a widget
+// (currently: terminal shells) and its scrollback.
+package blockcontroller
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var mu sync.Mutex
+var started = map[string]bool{}
+var scrollback = map[string][]string{}
+
+// ResyncController (re)starts the controller for a block. Here that only
+// records the block as started (see IsStarted); no shell process is spawned.
+// force restarts it even if already running.
+func ResyncController(ctx context.Context, tabId string, blockId string, opts any, force bool) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if started[blockId] && !force {
+		return nil
+	}
+	started[blockId] = true
+	return nil
+}
+
+// AppendScrollback records a line of output for blockId, for later reading
+// via GetScrollbackLines. The real pty plumbing that feeds this isn't wired
+// up yet, so scrollback is empty until something calls this.
+func AppendScrollback(blockId string, line string) {
+	mu.Lock()
+	defer mu.Unlock()
+	scrollback[blockId] = append(scrollback[blockId], line)
+}
+
+// IsStarted reports whether ResyncController has (re)started blockId's
+// controller at least once.
+func IsStarted(blockId string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return started[blockId]
+}
+
+var ansiEscapeRe = regexp.MustCompile("\x1b\\[[0-9;]*[A-Za-z]")
+
+// GetScrollbackLines returns up to tailLines trailing lines of a term
+// widget's scrollback (ANSI-stripped unless includeAnsi), capped to maxBytes.
+func GetScrollbackLines(ctx context.Context, blockId string, tailLines int, maxBytes int, includeAnsi bool) (string, error) {
+	mu.Lock()
+	lines := append([]string(nil), scrollback[blockId]...)
+	mu.Unlock()
+
+	if tailLines > 0 && len(lines) > tailLines {
+		lines = lines[len(lines)-tailLines:]
+	}
+
+	text := strings.Join(lines, "\n")
+	if !includeAnsi {
+		text = ansiEscapeRe.ReplaceAllString(text, "")
+	}
+	if maxBytes > 0 && len(text) > maxBytes {
+		text = text[len(text)-maxBytes:]
+	}
+	return text, nil
+}
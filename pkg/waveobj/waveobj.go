@@ -0,0 +1,92 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package waveobj defines the core object model (blocks, tabs, and the
+// layout actions used to arrange them) shared by the store and the AI
+// tool callbacks that mutate it.
+package waveobj
+
+import "context"
+
+const (
+	OType_Block = "block"
+	OType_Tab   = "tab"
+)
+
+// ORef is a reference to a stored wave object, identified by its type and ID.
+type ORef struct {
+	OType string `json:"otype"`
+	OID   string `json:"oid"`
+}
+
+func MakeORef(otype string, oid string) ORef {
+	return ORef{OType: otype, OID: oid}
+}
+
+// BlockDef is the initial definition used to create a new block.
+type BlockDef struct {
+	Meta map[string]any `json:"meta"`
+}
+
+// Block is a single widget instance.
+type Block struct {
+	OID   string         `json:"oid"`
+	TabId string         `json:"tabid,omitempty"`
+	Meta  map[string]any `json:"meta"`
+}
+
+// Tab holds the set of blocks open in a tab plus tab-level metadata
+// (AI layout snapshots are stored here, under a dedicated meta key).
+type Tab struct {
+	OID      string         `json:"oid"`
+	BlockIds []string       `json:"blockids"`
+	Meta     map[string]any `json:"meta"`
+}
+
+// LayoutActionDataType values live in the wcore package (the layout engine
+// owns the vocabulary of action types); LayoutActionData itself is shared
+// here since both wcore (producer/consumer) and callers that build actions
+// need the same wire shape.
+type LayoutActionData struct {
+	ActionType    string   `json:"actiontype"`
+	BlockId       string   `json:"blockid"`
+	TargetBlockId string   `json:"targetblockid,omitempty"`
+	Position      string   `json:"position,omitempty"`
+	Focused       bool     `json:"focused,omitempty"`
+	Ratio         *float64 `json:"ratio,omitempty"`
+	Delta         *float64 `json:"delta,omitempty"`
+}
+
+// WaveObjUpdate is a single changed-object event queued during a request and
+// flushed to subscribers once the request finishes.
+type WaveObjUpdate struct {
+	ORef ORef `json:"oref"`
+}
+
+type updatesCtxKey struct{}
+
+// ContextWithUpdates returns a context that accumulates WaveObjUpdates made
+// during the request, so callers can flush them in one broadcast at the end.
+func ContextWithUpdates(ctx context.Context) context.Context {
+	updates := &[]WaveObjUpdate{}
+	return context.WithValue(ctx, updatesCtxKey{}, updates)
+}
+
+// ContextAddUpdate records an update against a context created with
+// ContextWithUpdates. It is a no-op if the context wasn't set up that way.
+func ContextAddUpdate(ctx context.Context, update WaveObjUpdate) {
+	updates, ok := ctx.Value(updatesCtxKey{}).(*[]WaveObjUpdate)
+	if !ok {
+		return
+	}
+	*updates = append(*updates, update)
+}
+
+// ContextGetUpdatesRtn returns the updates accumulated on this context so far.
+func ContextGetUpdatesRtn(ctx context.Context) []WaveObjUpdate {
+	updates, ok := ctx.Value(updatesCtxKey{}).(*[]WaveObjUpdate)
+	if !ok {
+		return nil
+	}
+	return *updates
+}
@@ -0,0 +1,468 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package wcore implements the core block/layout operations used by the AI
+// tool callbacks: creating and resolving widgets, and applying layout
+// actions (insert, split, move, resize, remove) to a tab's layout tree.
+package wcore
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+	"github.com/wavetermdev/waveterm/pkg/wps"
+	"github.com/wavetermdev/waveterm/pkg/wstore"
+)
+
+const (
+	LayoutActionDataType_Insert          = "insert"
+	LayoutActionDataType_Remove          = "remove"
+	LayoutActionDataType_SplitHorizontal = "splithorizontal"
+	LayoutActionDataType_SplitVertical   = "splitvertical"
+	LayoutActionDataType_MoveHorizontal  = "movehorizontal"
+	LayoutActionDataType_MoveVertical    = "movevertical"
+	LayoutActionDataType_Resize          = "resize"
+)
+
+func newBlockId() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// CreateBlock creates a new block in the given tab from blockDef. opts is
+// reserved for per-widget-type creation options (unused so far).
+func CreateBlock(ctx context.Context, tabId string, blockDef *waveobj.BlockDef, opts any) (*waveobj.Block, error) {
+	block := &waveobj.Block{OID: newBlockId(), Meta: blockDef.Meta, TabId: tabId}
+	wstore.PutBlock(tabId, block)
+	waveobj.ContextAddUpdate(ctx, waveobj.WaveObjUpdate{ORef: waveobj.MakeORef(waveobj.OType_Block, block.OID)})
+	return block, nil
+}
+
+// DeleteBlock removes a block from the store. Layout removal is handled
+// separately by queuing a LayoutActionDataType_Remove action before calling
+// DeleteBlock, matching how callers already sequence widget_close.
+func DeleteBlock(ctx context.Context, blockId string, recursive bool) error {
+	block, err := wstore.DBMustGet[*waveobj.Block](ctx, blockId)
+	if err != nil {
+		return err
+	}
+	wstore.DeleteBlockRaw(block.TabId, blockId)
+	waveobj.ContextAddUpdate(ctx, waveobj.WaveObjUpdate{ORef: waveobj.MakeORef(waveobj.OType_Block, blockId)})
+	return nil
+}
+
+// SendWaveObjUpdate pushes a single update immediately, outside of a
+// request's batched ContextWithUpdates flush (used right after a meta write
+// that the caller wants reflected without waiting on the request's own flush).
+func SendWaveObjUpdate(oref waveobj.ORef) {
+	wps.Broker.SendUpdateEvents([]waveobj.WaveObjUpdate{{ORef: oref}})
+}
+
+// ResolveBlockIdFromPrefix resolves a short (e.g. 8-character) widget ID to
+// the full block ID of the unique block in tabId whose ID starts with it.
+func ResolveBlockIdFromPrefix(ctx context.Context, tabId string, prefix string) (string, error) {
+	tab, err := wstore.DBMustGet[*waveobj.Tab](ctx, tabId)
+	if err != nil {
+		return "", err
+	}
+	var match string
+	for _, blockId := range tab.BlockIds {
+		if strings.HasPrefix(blockId, prefix) {
+			if match != "" {
+				return "", fmt.Errorf("widget id %q is ambiguous (matches multiple widgets)", prefix)
+			}
+			match = blockId
+		}
+	}
+	if match == "" {
+		return "", fmt.Errorf("no widget found matching id %q", prefix)
+	}
+	return match, nil
+}
+
+// GetBlockIdsForTab returns the IDs of all blocks currently open in tabId.
+func GetBlockIdsForTab(ctx context.Context, tabId string) ([]string, error) {
+	tab, err := wstore.DBMustGet[*waveobj.Tab](ctx, tabId)
+	if err != nil {
+		return nil, err
+	}
+	return tab.BlockIds, nil
+}
+
+// LayoutNode is a node in a tab's layout tree: either a "leaf" holding a
+// single block, or a "row"/"col" split holding children sized by fraction
+// (Size) of their parent.
+type LayoutNode struct {
+	NodeType string        `json:"nodetype"`
+	BlockId  string        `json:"blockid,omitempty"`
+	Size     float64       `json:"size"`
+	Children []*LayoutNode `json:"children,omitempty"`
+}
+
+var layoutMu sync.Mutex
+var layoutRoots = map[string]*LayoutNode{}
+
+// GetLayoutTreeForTab returns the root of tabId's layout tree (nil if the
+// tab has no widgets placed yet).
+func GetLayoutTreeForTab(ctx context.Context, tabId string) (*LayoutNode, error) {
+	layoutMu.Lock()
+	defer layoutMu.Unlock()
+	return layoutRoots[tabId], nil
+}
+
+func clamp(v, lo, hi float64) float64 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+func findNodeAndParent(node *LayoutNode, parent *LayoutNode, blockId string) (*LayoutNode, *LayoutNode) {
+	if node == nil {
+		return nil, nil
+	}
+	if node.NodeType == "leaf" {
+		if node.BlockId == blockId {
+			return node, parent
+		}
+		return nil, nil
+	}
+	for _, child := range node.Children {
+		if found, foundParent := findNodeAndParent(child, node, blockId); found != nil {
+			return found, foundParent
+		}
+	}
+	return nil, nil
+}
+
+func indexOfChild(children []*LayoutNode, target *LayoutNode) int {
+	for i, c := range children {
+		if c == target {
+			return i
+		}
+	}
+	return -1
+}
+
+// splitLeaf inserts a new leaf for newBlockId relative to targetBlockId. If
+// the target's parent already splits in the requested direction, the new
+// leaf is added as a sibling; otherwise the target is wrapped in a new split
+// node of that direction, exactly as widget_open does for a fresh split.
+func splitLeaf(root *LayoutNode, targetBlockId string, newBlockId string, nodeType string, position string) (*LayoutNode, error) {
+	newLeaf := &LayoutNode{NodeType: "leaf", BlockId: newBlockId}
+
+	if root == nil {
+		newLeaf.Size = 1.0
+		return newLeaf, nil
+	}
+
+	target, parent := findNodeAndParent(root, nil, targetBlockId)
+	if target == nil {
+		return nil, fmt.Errorf("target widget %s not found in layout", targetBlockId)
+	}
+
+	if parent != nil && parent.NodeType == nodeType {
+		idx := indexOfChild(parent.Children, target)
+		insertAt := idx + 1
+		if position == "before" {
+			insertAt = idx
+		}
+		n := len(parent.Children)
+		newLeaf.Size = 1.0 / float64(n+1)
+		scale := float64(n) / float64(n+1)
+		for _, c := range parent.Children {
+			c.Size *= scale
+		}
+		children := make([]*LayoutNode, 0, n+1)
+		children = append(children, parent.Children[:insertAt]...)
+		children = append(children, newLeaf)
+		children = append(children, parent.Children[insertAt:]...)
+		parent.Children = children
+		return root, nil
+	}
+
+	wrapper := &LayoutNode{NodeType: nodeType, Size: target.Size}
+	wrappedTarget := &LayoutNode{NodeType: target.NodeType, BlockId: target.BlockId, Size: 0.5, Children: target.Children}
+	newLeaf.Size = 0.5
+	if position == "before" {
+		wrapper.Children = []*LayoutNode{newLeaf, wrappedTarget}
+	} else {
+		wrapper.Children = []*LayoutNode{wrappedTarget, newLeaf}
+	}
+
+	if parent == nil {
+		return wrapper, nil
+	}
+	parent.Children[indexOfChild(parent.Children, target)] = wrapper
+	return root, nil
+}
+
+// removeLeaf removes the leaf for blockId from the tree, collapsing any
+// parent split left with a single remaining child. Returns the (possibly
+// new) root and whether blockId was found.
+func removeLeaf(root *LayoutNode, blockId string) (*LayoutNode, bool) {
+	if root == nil {
+		return nil, false
+	}
+	if root.NodeType == "leaf" {
+		if root.BlockId == blockId {
+			return nil, true
+		}
+		return root, false
+	}
+
+	for i, child := range root.Children {
+		var found bool
+		var newChild *LayoutNode
+		if child.NodeType == "leaf" && child.BlockId == blockId {
+			found = true
+			newChild = nil
+		} else if child.NodeType != "leaf" {
+			newChild, found = removeLeaf(child, blockId)
+		}
+		if !found {
+			continue
+		}
+
+		removedSize := child.Size
+		replacement := childrenOrEmpty(newChild)
+		siblings := make([]*LayoutNode, 0, len(root.Children)-1+len(replacement))
+		siblings = append(siblings, root.Children[:i]...)
+		siblings = append(siblings, replacement...)
+		siblings = append(siblings, root.Children[i+1:]...)
+		root.Children = siblings
+
+		if newChild == nil && removedSize > 0 {
+			keep := 1.0 - removedSize
+			if keep > 0.0001 {
+				for _, sib := range root.Children {
+					sib.Size /= keep
+				}
+			}
+		}
+
+		if len(root.Children) == 1 {
+			only := root.Children[0]
+			only.Size = root.Size
+			return only, true
+		}
+		return root, true
+	}
+	return root, false
+}
+
+func childrenOrEmpty(n *LayoutNode) []*LayoutNode {
+	if n == nil {
+		return nil
+	}
+	return []*LayoutNode{n}
+}
+
+func resizeNode(root *LayoutNode, action waveobj.LayoutActionData) error {
+	if root == nil {
+		return fmt.Errorf("tab has no layout to resize within")
+	}
+	node, parent := findNodeAndParent(root, nil, action.BlockId)
+	if node == nil {
+		return fmt.Errorf("widget %s not found in layout", action.BlockId)
+	}
+	if parent == nil {
+		return fmt.Errorf("widget %s has no parent split to resize within", action.BlockId)
+	}
+
+	if action.Ratio != nil {
+		newSize := clamp(*action.Ratio, 0.01, 0.99)
+		oldSize := node.Size
+		oldRemaining := 1.0 - oldSize
+		newRemaining := 1.0 - newSize
+		for _, sib := range parent.Children {
+			if sib == node {
+				continue
+			}
+			if oldRemaining > 0.0001 {
+				sib.Size = sib.Size / oldRemaining * newRemaining
+			} else if len(parent.Children) > 1 {
+				sib.Size = newRemaining / float64(len(parent.Children)-1)
+			}
+		}
+		node.Size = newSize
+		return nil
+	}
+
+	if action.TargetBlockId == action.BlockId {
+		return fmt.Errorf("widget %s cannot be resized relative to itself", action.BlockId)
+	}
+
+	target, targetParent := findNodeAndParent(root, nil, action.TargetBlockId)
+	if target == nil {
+		return fmt.Errorf("target widget %s not found in layout", action.TargetBlockId)
+	}
+	if targetParent != parent {
+		return fmt.Errorf("widget %s and target widget %s are not siblings in the layout", action.BlockId, action.TargetBlockId)
+	}
+
+	delta := *action.Delta
+	newNodeSize := clamp(node.Size+delta, 0.01, 0.99)
+	actualDelta := newNodeSize - node.Size
+	node.Size = newNodeSize
+	target.Size = clamp(target.Size-actualDelta, 0.01, 0.99)
+	return nil
+}
+
+// QueueLayoutActionForTab applies a single layout action to tabId's layout
+// tree. Despite the name (kept for parity with how callers use it alongside
+// ContextWithUpdates), this implementation applies the change immediately;
+// there is no separate flush step for the tree itself, only for the
+// WaveObjUpdate events callers broadcast afterward.
+func QueueLayoutActionForTab(ctx context.Context, tabId string, action waveobj.LayoutActionData) error {
+	layoutMu.Lock()
+	defer layoutMu.Unlock()
+
+	root := layoutRoots[tabId]
+
+	switch action.ActionType {
+	case LayoutActionDataType_Insert:
+		if root == nil {
+			layoutRoots[tabId] = &LayoutNode{NodeType: "leaf", BlockId: action.BlockId, Size: 1.0}
+			return nil
+		}
+		root.Size = 0.5
+		layoutRoots[tabId] = &LayoutNode{
+			NodeType: "row",
+			Size:     1.0,
+			Children: []*LayoutNode{root, {NodeType: "leaf", BlockId: action.BlockId, Size: 0.5}},
+		}
+		return nil
+
+	case LayoutActionDataType_SplitHorizontal, LayoutActionDataType_SplitVertical:
+		nodeType := "row"
+		if action.ActionType == LayoutActionDataType_SplitVertical {
+			nodeType = "col"
+		}
+		newRoot, err := splitLeaf(root, action.TargetBlockId, action.BlockId, nodeType, action.Position)
+		if err != nil {
+			return err
+		}
+		layoutRoots[tabId] = newRoot
+		return nil
+
+	case LayoutActionDataType_Remove:
+		newRoot, _ := removeLeaf(root, action.BlockId)
+		layoutRoots[tabId] = newRoot
+		return nil
+
+	case LayoutActionDataType_MoveHorizontal, LayoutActionDataType_MoveVertical:
+		trimmedRoot, found := removeLeaf(root, action.BlockId)
+		if !found {
+			return fmt.Errorf("widget %s not found in layout", action.BlockId)
+		}
+		nodeType := "row"
+		if action.ActionType == LayoutActionDataType_MoveVertical {
+			nodeType = "col"
+		}
+		newRoot, err := splitLeaf(trimmedRoot, action.TargetBlockId, action.BlockId, nodeType, action.Position)
+		if err != nil {
+			return err
+		}
+		layoutRoots[tabId] = newRoot
+		return nil
+
+	case LayoutActionDataType_Resize:
+		return resizeNode(root, action)
+
+	default:
+		return fmt.Errorf("unknown layout action type %q", action.ActionType)
+	}
+}
+
+// ReadFileSnippet returns up to maxBytes from the start of the file a
+// preview block points at (via its "file" meta), for the widget_read tool.
+func ReadFileSnippet(ctx context.Context, blockId string, maxBytes int) (string, error) {
+	block, err := wstore.DBMustGet[*waveobj.Block](ctx, blockId)
+	if err != nil {
+		return "", err
+	}
+	filePath, _ := block.Meta["file"].(string)
+	if filePath == "" {
+		return "", nil
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", filePath, err)
+	}
+	defer f.Close()
+
+	buf := make([]byte, maxBytes)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	return string(buf[:n]), nil
+}
+
+// PlotSample is the latest observed value for a cpuplot widget.
+type PlotSample struct {
+	Ts    int64   `json:"ts"`
+	Value float64 `json:"value"`
+}
+
+var plotMu sync.Mutex
+var latestPlotSamples = map[string]PlotSample{}
+
+// SetLatestPlotSample records the latest sample for a cpuplot widget. It is
+// the write side of GetLatestPlotSample; nothing in this module calls it, so
+// GetLatestPlotSample reports no sample until a caller records one.
+func SetLatestPlotSample(blockId string, sample PlotSample) {
+	plotMu.Lock()
+	defer plotMu.Unlock()
+	latestPlotSamples[blockId] = sample
+}
+
+// GetLatestPlotSample returns the latest recorded sample for a cpuplot
+// widget, or {"available": false} if none has been recorded yet.
+func GetLatestPlotSample(ctx context.Context, blockId string) (map[string]any, error) {
+	plotMu.Lock()
+	defer plotMu.Unlock()
+	sample, ok := latestPlotSamples[blockId]
+	if !ok {
+		return map[string]any{"available": false}, nil
+	}
+	return map[string]any{"available": true, "ts": sample.Ts, "value": sample.Value}, nil
+}
+
+var connMu sync.Mutex
+var knownConnections = map[string]bool{}
+
+// RegisterConnection marks connName as currently available. widget_open (and
+// the batch open op) call this once a widget is successfully created against
+// that connection, since this tree has no real SSH/WSL resolver to confirm
+// connections up front - a successful open is the closest stand-in.
+func RegisterConnection(connName string) {
+	connMu.Lock()
+	defer connMu.Unlock()
+	knownConnections[connName] = true
+}
+
+// ConnectionExists reports whether connName is currently available. The
+// empty string (no connection set, i.e. the implicit local connection)
+// always exists.
+func ConnectionExists(connName string) bool {
+	if connName == "" {
+		return true
+	}
+	connMu.Lock()
+	defer connMu.Unlock()
+	return knownConnections[connName]
+}
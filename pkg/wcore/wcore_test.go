@@ -0,0 +1,78 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package wcore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+)
+
+func TestResizeDeltaRejectsSameWidget(t *testing.T) {
+	ctx := context.Background()
+	tabId := "test-resize-delta-rejects-self"
+
+	mustQueue(t, ctx, tabId, waveobj.LayoutActionData{ActionType: LayoutActionDataType_Insert, BlockId: "a"})
+	mustQueue(t, ctx, tabId, waveobj.LayoutActionData{ActionType: LayoutActionDataType_SplitHorizontal, BlockId: "b", TargetBlockId: "a", Position: "after"})
+
+	delta := 0.1
+	err := QueueLayoutActionForTab(ctx, tabId, waveobj.LayoutActionData{ActionType: LayoutActionDataType_Resize, BlockId: "a", TargetBlockId: "a", Delta: &delta})
+	if err == nil {
+		t.Fatal("expected an error resizing a widget relative to itself, got nil")
+	}
+
+	root, _ := GetLayoutTreeForTab(ctx, tabId)
+	if root.Children[0].Size != 0.5 || root.Children[1].Size != 0.5 {
+		t.Fatalf("layout should be unchanged after a rejected resize, got sizes %v/%v", root.Children[0].Size, root.Children[1].Size)
+	}
+}
+
+func TestResizeDeltaRedistributesSibling(t *testing.T) {
+	ctx := context.Background()
+	tabId := "test-resize-delta-sibling"
+
+	mustQueue(t, ctx, tabId, waveobj.LayoutActionData{ActionType: LayoutActionDataType_Insert, BlockId: "a"})
+	mustQueue(t, ctx, tabId, waveobj.LayoutActionData{ActionType: LayoutActionDataType_SplitHorizontal, BlockId: "b", TargetBlockId: "a", Position: "after"})
+
+	delta := 0.2
+	mustQueue(t, ctx, tabId, waveobj.LayoutActionData{ActionType: LayoutActionDataType_Resize, BlockId: "a", TargetBlockId: "b", Delta: &delta})
+
+	root, _ := GetLayoutTreeForTab(ctx, tabId)
+	a, b := root.Children[0], root.Children[1]
+	if a.Size < 0.69 || a.Size > 0.71 {
+		t.Errorf("expected widget a to grow to ~0.7, got %v", a.Size)
+	}
+	if b.Size < 0.29 || b.Size > 0.31 {
+		t.Errorf("expected widget b to shrink to ~0.3, got %v", b.Size)
+	}
+}
+
+func TestResizeRatioRenormalizesSiblings(t *testing.T) {
+	ctx := context.Background()
+	tabId := "test-resize-ratio-siblings"
+
+	mustQueue(t, ctx, tabId, waveobj.LayoutActionData{ActionType: LayoutActionDataType_Insert, BlockId: "a"})
+	mustQueue(t, ctx, tabId, waveobj.LayoutActionData{ActionType: LayoutActionDataType_SplitHorizontal, BlockId: "b", TargetBlockId: "a", Position: "after"})
+	mustQueue(t, ctx, tabId, waveobj.LayoutActionData{ActionType: LayoutActionDataType_SplitHorizontal, BlockId: "c", TargetBlockId: "b", Position: "after"})
+
+	ratio := 0.5
+	mustQueue(t, ctx, tabId, waveobj.LayoutActionData{ActionType: LayoutActionDataType_Resize, BlockId: "a", Ratio: &ratio})
+
+	root, _ := GetLayoutTreeForTab(ctx, tabId)
+	total := root.Children[0].Size + root.Children[1].Size + root.Children[2].Size
+	if total < 0.99 || total > 1.01 {
+		t.Fatalf("sibling sizes should still sum to ~1.0 after a ratio resize, got %v", total)
+	}
+	if root.Children[0].Size < 0.49 || root.Children[0].Size > 0.51 {
+		t.Errorf("expected widget a to be set to ratio 0.5, got %v", root.Children[0].Size)
+	}
+}
+
+func mustQueue(t *testing.T, ctx context.Context, tabId string, action waveobj.LayoutActionData) {
+	t.Helper()
+	if err := QueueLayoutActionForTab(ctx, tabId, action); err != nil {
+		t.Fatalf("failed to queue layout action %+v: %v", action, err)
+	}
+}
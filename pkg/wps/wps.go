@@ -0,0 +1,21 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package wps is the update broker that fans changed-object events out to
+// subscribed frontend clients.
+package wps
+
+import "github.com/wavetermdev/waveterm/pkg/waveobj"
+
+// UpdateBroker publishes WaveObjUpdate events. This in-memory implementation
+// has no subscribers wired up yet; it exists so callers can flush their
+// accumulated updates through the same call they'll use once subscriptions
+// are added.
+type UpdateBroker struct{}
+
+func (b *UpdateBroker) SendUpdateEvents(updates []waveobj.WaveObjUpdate) {
+	// no subscribers yet; this is the single flush point future event
+	// delivery (e.g. websocket push to the frontend) will hook into.
+}
+
+var Broker = &UpdateBroker{}
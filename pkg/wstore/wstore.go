@@ -0,0 +1,138 @@
+// Copyright 2025, Command Line Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package wstore is the object store for blocks and tabs. It is an
+// in-memory backing store; a real deployment would persist this to disk,
+// but the access patterns (get-by-id, merge-meta) are the same.
+package wstore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/wavetermdev/waveterm/pkg/waveobj"
+)
+
+type store struct {
+	mu     sync.Mutex
+	blocks map[string]*waveobj.Block
+	tabs   map[string]*waveobj.Tab
+}
+
+var globalStore = &store{
+	blocks: make(map[string]*waveobj.Block),
+	tabs:   make(map[string]*waveobj.Tab),
+}
+
+// EnsureTab returns the tab with the given id, creating an empty one if it
+// doesn't exist yet (tabs aren't created through this package's AI-facing
+// surface, so callers bootstrap them lazily on first use).
+func EnsureTab(tabId string) *waveobj.Tab {
+	globalStore.mu.Lock()
+	defer globalStore.mu.Unlock()
+	tab, ok := globalStore.tabs[tabId]
+	if !ok {
+		tab = &waveobj.Tab{OID: tabId, Meta: map[string]any{}}
+		globalStore.tabs[tabId] = tab
+	}
+	return tab
+}
+
+// PutBlock inserts or replaces a block and links it into its tab's block list.
+func PutBlock(tabId string, block *waveobj.Block) {
+	globalStore.mu.Lock()
+	defer globalStore.mu.Unlock()
+	globalStore.blocks[block.OID] = block
+	tab, ok := globalStore.tabs[tabId]
+	if !ok {
+		tab = &waveobj.Tab{OID: tabId, Meta: map[string]any{}}
+		globalStore.tabs[tabId] = tab
+	}
+	tab.BlockIds = append(tab.BlockIds, block.OID)
+}
+
+// DeleteBlockRaw removes a block from the store and unlinks it from its tab.
+func DeleteBlockRaw(tabId string, blockId string) {
+	globalStore.mu.Lock()
+	defer globalStore.mu.Unlock()
+	delete(globalStore.blocks, blockId)
+	tab, ok := globalStore.tabs[tabId]
+	if !ok {
+		return
+	}
+	newIds := tab.BlockIds[:0]
+	for _, id := range tab.BlockIds {
+		if id != blockId {
+			newIds = append(newIds, id)
+		}
+	}
+	tab.BlockIds = newIds
+}
+
+// DBMustGet loads a block or tab by id. T must be *waveobj.Block or *waveobj.Tab.
+func DBMustGet[T any](ctx context.Context, oid string) (T, error) {
+	var zero T
+	globalStore.mu.Lock()
+	defer globalStore.mu.Unlock()
+
+	switch any(zero).(type) {
+	case *waveobj.Block:
+		block, ok := globalStore.blocks[oid]
+		if !ok {
+			return zero, fmt.Errorf("block %s not found", oid)
+		}
+		return any(block).(T), nil
+	case *waveobj.Tab:
+		tab, ok := globalStore.tabs[oid]
+		if !ok {
+			return zero, fmt.Errorf("tab %s not found", oid)
+		}
+		return any(tab).(T), nil
+	default:
+		return zero, fmt.Errorf("DBMustGet: unsupported type %T", zero)
+	}
+}
+
+// UpdateObjectMeta merges (or replaces) the meta map on a block or tab and
+// records the change on ctx so it can be broadcast via wps.
+func UpdateObjectMeta(ctx context.Context, oref waveobj.ORef, meta map[string]any, merge bool) error {
+	globalStore.mu.Lock()
+	defer globalStore.mu.Unlock()
+
+	var target map[string]any
+	switch oref.OType {
+	case waveobj.OType_Block:
+		block, ok := globalStore.blocks[oref.OID]
+		if !ok {
+			return fmt.Errorf("block %s not found", oref.OID)
+		}
+		if block.Meta == nil {
+			block.Meta = map[string]any{}
+		}
+		target = block.Meta
+	case waveobj.OType_Tab:
+		tab, ok := globalStore.tabs[oref.OID]
+		if !ok {
+			return fmt.Errorf("tab %s not found", oref.OID)
+		}
+		if tab.Meta == nil {
+			tab.Meta = map[string]any{}
+		}
+		target = tab.Meta
+	default:
+		return fmt.Errorf("UpdateObjectMeta: unsupported otype %q", oref.OType)
+	}
+
+	if !merge {
+		for k := range target {
+			delete(target, k)
+		}
+	}
+	for k, v := range meta {
+		target[k] = v
+	}
+
+	waveobj.ContextAddUpdate(ctx, waveobj.WaveObjUpdate{ORef: oref})
+	return nil
+}